@@ -0,0 +1,269 @@
+package lib
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// startTLSProtocols is the protocol dispatch table for DialStartTLS,
+// alongside tlsVersions/cipherSuites. Each handler reads the plaintext
+// greeting, issues the protocol's upgrade command, and verifies the
+// affirmative response.
+var startTLSProtocols = map[string]func(net.Conn) error{
+	"smtp":     startTLSSMTP,
+	"imap":     startTLSIMAP,
+	"pop3":     startTLSPOP3,
+	"xmpp":     startTLSXMPP,
+	"ldap":     startTLSLDAP,
+	"postgres": startTLSPostgres,
+	"mysql":    startTLSMySQL,
+}
+
+// DialStartTLS connects to addr in the clear, negotiates the given
+// protocol's STARTTLS upgrade, and wraps the resulting connection with
+// tls.Client. This lets certigo inspect certificates and TLS parameters
+// for services that don't speak TLS directly on connect. timeout bounds
+// both the initial dial and the STARTTLS negotiation, the same way
+// ScanTLS bounds its probes; a timeout of zero means no deadline.
+func DialStartTLS(network, addr, protocol string, timeout time.Duration, config *tls.Config) (*tls.Conn, error) {
+	upgrade, ok := startTLSProtocols[protocol]
+	if !ok {
+		return nil, fmt.Errorf("unsupported STARTTLS protocol %q", protocol)
+	}
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	if err := upgrade(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("STARTTLS negotiation failed: %w", err)
+	}
+	// Clear the deadline before the TLS handshake, which manages its own
+	// timing via the caller-supplied config/context.
+	if err := conn.SetDeadline(time.Time{}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tls.Client(conn, config), nil
+}
+
+func writeLine(conn net.Conn, line string) error {
+	_, err := fmt.Fprintf(conn, "%s\r\n", line)
+	return err
+}
+
+// startTLSSMTP issues the EHLO/STARTTLS ladder from RFC 3207.
+func startTLSSMTP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := readSMTPResponse(r); err != nil { // 220 greeting
+		return err
+	}
+	if err := writeLine(conn, "EHLO certigo"); err != nil {
+		return err
+	}
+	if _, err := readSMTPResponse(r); err != nil { // 250 capabilities
+		return err
+	}
+	if err := writeLine(conn, "STARTTLS"); err != nil {
+		return err
+	}
+	code, err := readSMTPResponse(r)
+	if err != nil {
+		return err
+	}
+	if code != "220" {
+		return fmt.Errorf("server refused STARTTLS (code %s)", code)
+	}
+	return nil
+}
+
+// readSMTPResponse reads one (possibly multi-line) SMTP reply and
+// returns its three-digit status code.
+func readSMTPResponse(r *bufio.Reader) (string, error) {
+	var code string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if len(line) < 4 {
+			return "", fmt.Errorf("malformed SMTP response %q", line)
+		}
+		code = line[:3]
+		if line[3] == ' ' {
+			break
+		}
+	}
+	return code, nil
+}
+
+// startTLSIMAP issues a tagged "a1 STARTTLS" command from RFC 2595/3501.
+func startTLSIMAP(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := r.ReadString('\n'); err != nil { // "* OK" greeting
+		return err
+	}
+	if err := writeLine(conn, "a1 STARTTLS"); err != nil {
+		return err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(strings.TrimSpace(line), "a1 OK") {
+		return fmt.Errorf("server refused STARTTLS: %s", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// startTLSPOP3 issues the "STLS" command from RFC 2595.
+func startTLSPOP3(conn net.Conn) error {
+	r := bufio.NewReader(conn)
+	if _, err := r.ReadString('\n'); err != nil { // "+OK" greeting
+		return err
+	}
+	if err := writeLine(conn, "STLS"); err != nil {
+		return err
+	}
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("server refused STLS: %s", strings.TrimSpace(line))
+	}
+	return nil
+}
+
+// startTLSXMPP opens an XMPP stream and negotiates STARTTLS per RFC 6120.
+func startTLSXMPP(conn net.Conn) error {
+	_, err := fmt.Fprint(conn, "<?xml version='1.0'?><stream:stream to='certigo' "+
+		"xmlns='jabber:client' xmlns:stream='http://etherx.jabber.org/streams' version='1.0'>")
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 4096)
+	if _, err := conn.Read(buf); err != nil { // stream header + <stream:features>
+		return err
+	}
+	if _, err := fmt.Fprint(conn, "<starttls xmlns='urn:ietf:params:xml:ns:xmpp-tls'/>"); err != nil {
+		return err
+	}
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(string(buf[:n]), "<proceed") {
+		return fmt.Errorf("server refused STARTTLS: %s", string(buf[:n]))
+	}
+	return nil
+}
+
+// startTLSLDAPOID is the LDAP StartTLS extended operation's request OID,
+// assigned in RFC 4511/2830.
+const startTLSLDAPOID = "1.3.6.1.4.1.1466.20037"
+
+// startTLSLDAP sends an ExtendedRequest carrying the StartTLS OID and
+// checks for a success resultCode in the ExtendedResponse. It hand-rolls
+// just enough BER to build/read this one message rather than pulling in
+// a full ASN.1 LDAP implementation.
+func startTLSLDAP(conn net.Conn) error {
+	requestName := berTLV(0x80, []byte(startTLSLDAPOID)) // [0] requestName, context-primitive
+	extendedRequest := berTLV(0x77, requestName)         // [APPLICATION 23] ExtendedRequest
+	messageID := berTLV(0x02, []byte{1})                 // INTEGER messageID ::= 1
+	message := berTLV(0x30, append(messageID, extendedRequest...))
+
+	if _, err := conn.Write(message); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 4096)
+	n, err := conn.Read(resp)
+	if err != nil {
+		return err
+	}
+	// A successful ExtendedResponse carries resultCode ::= 0 as an
+	// ENUMERATED (tag 0x0A, length 1, value 0); rather than a full BER parse,
+	// just look for that success marker.
+	if !bytes.Contains(resp[:n], []byte{0x0a, 0x01, 0x00}) {
+		return fmt.Errorf("server refused StartTLS extended request")
+	}
+	return nil
+}
+
+// berTLV encodes a BER tag-length-value for short (<128 byte) values,
+// which is all the StartTLS extended request needs.
+func berTLV(tag byte, value []byte) []byte {
+	return append([]byte{tag, byte(len(value))}, value...)
+}
+
+// startTLSPostgres sends an SSLRequest message and checks for the 'S'
+// (supported) response, per the PostgreSQL protocol.
+func startTLSPostgres(conn net.Conn) error {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg[0:4], 8)
+	binary.BigEndian.PutUint32(msg[4:8], 80877103) // SSLRequest code
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[0] != 'S' {
+		return fmt.Errorf("server does not support SSL (got %q)", resp[0])
+	}
+	return nil
+}
+
+// MySQL capability flags needed to request a TLS upgrade.
+const (
+	mysqlClientSSL        = 0x00000800
+	mysqlClientProtocol41 = 0x00000200
+)
+
+// startTLSMySQL reads the server's initial handshake packet and replies
+// with an SSLRequest packet (a normal handshake response packet with only
+// the capability, max-packet-size and charset fields set and CLIENT_SSL
+// requested), after
+// which the client is expected to start the TLS handshake immediately.
+func startTLSMySQL(conn net.Conn) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+	seq := header[3]
+	if _, err := io.CopyN(io.Discard, conn, int64(length)); err != nil { // discard greeting body
+		return err
+	}
+
+	payload := make([]byte, 32)
+	binary.LittleEndian.PutUint32(payload[0:4], mysqlClientSSL|mysqlClientProtocol41)
+	binary.LittleEndian.PutUint32(payload[4:8], 1<<24-1) // max packet size
+	payload[8] = 33                                      // utf8_general_ci
+	// bytes 9-31 are reserved and must stay zero
+
+	out := make([]byte, 4+len(payload))
+	out[0] = byte(len(payload))
+	out[1] = byte(len(payload) >> 8)
+	out[2] = byte(len(payload) >> 16)
+	out[3] = seq + 1
+	copy(out[4:], payload)
+
+	_, err := conn.Write(out)
+	return err
+}
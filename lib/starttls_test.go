@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+)
+
+func TestBerTLV(t *testing.T) {
+	cases := []struct {
+		name  string
+		tag   byte
+		value []byte
+		want  []byte
+	}{
+		{"empty value", 0x02, nil, []byte{0x02, 0x00}},
+		{"short value", 0x80, []byte("hi"), []byte{0x80, 0x02, 'h', 'i'}},
+		{"ldap startTLS oid", 0x80, []byte(startTLSLDAPOID), append([]byte{0x80, byte(len(startTLSLDAPOID))}, []byte(startTLSLDAPOID)...)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := berTLV(c.tag, c.value)
+			if !bytes.Equal(got, c.want) {
+				t.Errorf("berTLV(%#x, %q) = %x, want %x", c.tag, c.value, got, c.want)
+			}
+		})
+	}
+}
+
+func TestStartTLSLDAP(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- startTLSLDAP(client) }()
+
+	req := make([]byte, 64)
+	n, err := server.Read(req)
+	if err != nil {
+		t.Fatalf("reading LDAP extended request: %v", err)
+	}
+	req = req[:n]
+
+	if req[0] != 0x30 {
+		t.Errorf("request does not open with a SEQUENCE tag: %x", req)
+	}
+	if !bytes.Contains(req, []byte(startTLSLDAPOID)) {
+		t.Errorf("request %x does not carry the StartTLS OID", req)
+	}
+
+	// A success resultCode (ENUMERATED 0), the marker startTLSLDAP looks for.
+	if _, err := server.Write([]byte{0x0a, 0x01, 0x00}); err != nil {
+		t.Fatalf("writing response: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("startTLSLDAP() = %v, want nil", err)
+	}
+}
+
+func TestStartTLSLDAPRejected(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- startTLSLDAP(client) }()
+
+	req := make([]byte, 64)
+	if _, err := server.Read(req); err != nil {
+		t.Fatalf("reading LDAP extended request: %v", err)
+	}
+
+	// resultCode 2 (protocolError), not the success marker.
+	if _, err := server.Write([]byte{0x0a, 0x01, 0x02}); err != nil {
+		t.Fatalf("writing response: %v", err)
+	}
+	if err := <-done; err == nil {
+		t.Error("startTLSLDAP() = nil error, want an error for a non-success resultCode")
+	}
+}
+
+func TestStartTLSMySQL(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- startTLSMySQL(client) }()
+
+	greeting := append([]byte{4, 0, 0, 0}, []byte{1, 2, 3, 4}...)
+	if _, err := server.Write(greeting); err != nil {
+		t.Fatalf("writing greeting: %v", err)
+	}
+
+	resp := make([]byte, 64)
+	n, err := server.Read(resp)
+	if err != nil {
+		t.Fatalf("reading SSLRequest packet: %v", err)
+	}
+	resp = resp[:n]
+
+	if len(resp) != 4+32 {
+		t.Fatalf("SSLRequest packet length = %d, want %d", len(resp), 4+32)
+	}
+	if resp[3] != greeting[3]+1 {
+		t.Errorf("sequence number = %d, want %d", resp[3], greeting[3]+1)
+	}
+	flags := binary.LittleEndian.Uint32(resp[4:8])
+	if flags&mysqlClientSSL == 0 {
+		t.Errorf("capability flags %#x do not set CLIENT_SSL", flags)
+	}
+	if flags&mysqlClientProtocol41 == 0 {
+		t.Errorf("capability flags %#x do not set CLIENT_PROTOCOL_41", flags)
+	}
+
+	if err := <-done; err != nil {
+		t.Errorf("startTLSMySQL() = %v, want nil", err)
+	}
+}
@@ -11,15 +11,38 @@ import (
 	"github.com/fatih/color"
 )
 
-// TLSDescription has the basic information about a TLS connection
+// TLSDescription has a Mozilla/SSL-Labs-style assessment of a TLS
+// connection: not just which version and cipher were negotiated, but
+// whether that choice is actually sound.
 type TLSDescription struct {
-	Version string `json:"version"`
-	Cipher  string `json:"cipher"`
+	Version        string      `json:"version"`
+	Cipher         string      `json:"cipher"`
+	ForwardSecrecy bool        `json:"forward_secrecy"`
+	AEAD           bool        `json:"aead"`
+	Authentication string      `json:"authentication,omitempty"`
+	KeyExchange    string      `json:"key_exchange,omitempty"`
+	Quality        string      `json:"quality"`
+	Warnings       []string    `json:"warnings,omitempty"`
+	Violations     []Violation `json:"violations,omitempty"`
+	// KeyExchangeGroup and SignatureScheme are only populated by
+	// EncodeTLSToTextWithDetails/EncodeTLSToObjectWithDetails, since the
+	// standard library doesn't expose either on tls.ConnectionState.
+	KeyExchangeGroup string `json:"key_exchange_group,omitempty"`
+	SignatureScheme  string `json:"signature_scheme,omitempty"`
+	// SupportedSuites is only populated by EncodeScanToObject, grouping
+	// every cipher suite a ScanTLS run found the server accepting by
+	// TLS version slug.
+	SupportedSuites map[string][]string `json:"supported_suites,omitempty"`
 }
 
 var tlsLayout = `** TLS Connection **
 Version: {{.Version}}
-Cipher Suite: {{.Cipher}}`
+Cipher Suite: {{.Cipher}}{{if .KeyExchangeGroup}}
+Key Exchange Group: {{.KeyExchangeGroup}}{{end}}{{if .SignatureScheme}}
+Signature Scheme: {{.SignatureScheme}}{{end}}{{if .Warnings}}
+Warnings:
+{{range .Warnings}}  - {{.}}
+{{end}}{{end}}`
 
 func tlscolor(d description) string {
 	c, ok := qualityColors[d.Quality]
@@ -32,10 +55,20 @@ func tlscolor(d description) string {
 // EncodeTLSToText returns a human readable string, suitable for certigo console output.
 func EncodeTLSToText(tcs *tls.ConnectionState) string {
 	version := lookup(tlsVersions, tcs.Version)
-	cipher := lookup(cipherSuites, tcs.CipherSuite)
+	cipher := lookupCipher(tcs.CipherSuite)
+	warnings := make([]string, len(cipher.Warnings))
+	for i, w := range cipher.Warnings {
+		warnings[i] = red.SprintFunc()(w)
+	}
 	description := TLSDescription{
-		Version: tlscolor(version),
-		Cipher:  tlscolor(explainCipher(cipher)),
+		Version:        tlscolor(version),
+		Cipher:         tlscolor(cipher.description),
+		ForwardSecrecy: cipher.ForwardSecrecy,
+		AEAD:           cipher.AEAD,
+		Authentication: cipher.Authentication,
+		KeyExchange:    cipher.KeyExchange,
+		Quality:        qualityNames[cipher.Quality],
+		Warnings:       warnings,
 	}
 	t := template.New("TLS template")
 	t, err := t.Parse(tlsLayout)
@@ -57,11 +90,111 @@ func EncodeTLSToText(tcs *tls.ConnectionState) string {
 // EncodeTLSToObject returns a JSON-marshallable description of a TLS connection
 func EncodeTLSToObject(t *tls.ConnectionState) interface{} {
 	version := lookup(tlsVersions, t.Version)
-	cipher := lookup(cipherSuites, t.CipherSuite)
+	cipher := lookupCipher(t.CipherSuite)
 	return &TLSDescription{
-		version.Slug,
-		cipher.Slug,
+		Version:        version.Slug,
+		Cipher:         cipher.Slug,
+		ForwardSecrecy: cipher.ForwardSecrecy,
+		AEAD:           cipher.AEAD,
+		Authentication: cipher.Authentication,
+		KeyExchange:    cipher.KeyExchange,
+		Quality:        qualityNames[cipher.Quality],
+		Warnings:       cipher.Warnings,
+	}
+}
+
+// EncodeTLSToTextWithProfile behaves like EncodeTLSToText, but also checks
+// the connection against a named profile (e.g. "modern") and prints any
+// violations in red below the connection summary, for use with
+// --verify-against-profile.
+func EncodeTLSToTextWithProfile(tcs *tls.ConnectionState, profile string) (string, []Violation, error) {
+	violations, err := VerifyTLSAgainstProfile(tcs, profile)
+	if err != nil {
+		return "", nil, err
+	}
+	text := EncodeTLSToText(tcs)
+	if len(violations) == 0 {
+		return text, violations, nil
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(text)
+	buffer.WriteString("\nProfile violations:\n")
+	for _, v := range violations {
+		buffer.WriteString(red.SprintFunc()(fmt.Sprintf("  - %s: %s\n", v.Parameter, v.Message)))
+	}
+	return buffer.String(), violations, nil
+}
+
+// EncodeTLSToObjectWithProfile behaves like EncodeTLSToObject, but also
+// checks the connection against a named profile and includes the
+// violations (if any) in the returned object, for use with
+// --verify-against-profile.
+func EncodeTLSToObjectWithProfile(tcs *tls.ConnectionState, profile string) (interface{}, []Violation, error) {
+	violations, err := VerifyTLSAgainstProfile(tcs, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	d := EncodeTLSToObject(tcs).(*TLSDescription)
+	d.Violations = violations
+	return d, violations, nil
+}
+
+// EncodeTLSToTextWithDetails behaves like EncodeTLSToText, but also prints
+// the negotiated key-exchange group and signature scheme captured by
+// DialWithDetails/DetectKeyExchangeGroup, since tls.ConnectionState
+// doesn't expose either directly.
+func EncodeTLSToTextWithDetails(tcs *tls.ConnectionState, details *HandshakeDetails) string {
+	version := lookup(tlsVersions, tcs.Version)
+	cipher := lookupCipher(tcs.CipherSuite)
+	warnings := make([]string, len(cipher.Warnings))
+	for i, w := range cipher.Warnings {
+		warnings[i] = red.SprintFunc()(w)
+	}
+	description := TLSDescription{
+		Version:        tlscolor(version),
+		Cipher:         tlscolor(cipher.description),
+		ForwardSecrecy: cipher.ForwardSecrecy,
+		AEAD:           cipher.AEAD,
+		Authentication: cipher.Authentication,
+		KeyExchange:    cipher.KeyExchange,
+		Quality:        qualityNames[cipher.Quality],
+		Warnings:       warnings,
+	}
+	if details != nil {
+		if details.KeyExchangeGroup.Name != "" {
+			description.KeyExchangeGroup = tlscolor(details.KeyExchangeGroup)
+		}
+		if details.SignatureScheme.Name != "" {
+			description.SignatureScheme = tlscolor(details.SignatureScheme)
+		}
+	}
+	t := template.New("TLS template")
+	t, err := t.Parse(tlsLayout)
+	if err != nil {
+		// Should never happen
+		panic(err)
+	}
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	err = t.Execute(w, description)
+	if err != nil {
+		// Should never happen
+		panic(err)
+	}
+	w.Flush()
+	return string(buffer.Bytes())
+}
+
+// EncodeTLSToObjectWithDetails behaves like EncodeTLSToObject, but also
+// includes the negotiated key-exchange group and signature scheme
+// captured by DialWithDetails/DetectKeyExchangeGroup.
+func EncodeTLSToObjectWithDetails(tcs *tls.ConnectionState, details *HandshakeDetails) interface{} {
+	d := EncodeTLSToObject(tcs).(*TLSDescription)
+	if details != nil {
+		d.KeyExchangeGroup = details.KeyExchangeGroup.Slug
+		d.SignatureScheme = details.SignatureScheme.Slug
 	}
+	return d
 }
 
 // Just a map lookup with a default
@@ -76,20 +209,34 @@ func lookup(descriptions map[uint16]description, what uint16) description {
 
 const (
 	insecure = iota
+	weak     = iota
 	ok       = iota
 	good     = iota
+	modern   = iota
 )
 
 type description struct {
 	Name    string // a human-friendly string
 	Slug    string // a machine-friendly string
-	Quality uint8  // insecure, ok, good
+	Quality uint8  // insecure, weak, ok, good, modern
+}
+
+// qualityNames gives the machine-friendly slug for each quality tier, used
+// in the JSON assessment output.
+var qualityNames = map[uint8]string{
+	insecure: "insecure",
+	weak:     "weak",
+	ok:       "ok",
+	good:     "good",
+	modern:   "modern",
 }
 
 var qualityColors = map[uint8]*color.Color{
 	insecure: red,
+	weak:     red,
 	ok:       yellow,
 	good:     green,
+	modern:   green,
 }
 
 var tlsVersions = map[uint16]description{
@@ -97,36 +244,119 @@ var tlsVersions = map[uint16]description{
 	tls.VersionTLS10: {"TLS 1.0", "tls_1_0", insecure},
 	tls.VersionTLS11: {"TLS 1.1", "tls_1_1", ok},
 	tls.VersionTLS12: {"TLS 1.2", "tls_1_2", good},
+	tls.VersionTLS13: {"TLS 1.3", "tls_1_3", modern},
+}
+
+// cipherInfo extends description with the per-suite assessment (forward
+// secrecy, AEAD, authentication and key exchange algorithms, and any
+// remediation warnings) used to build a machine-readable TLS report.
+type cipherInfo struct {
+	description
+	ForwardSecrecy bool
+	AEAD           bool
+	Authentication string
+	KeyExchange    string
+	Warnings       []string
 }
 
-// Fill in a human readable name, extracted from the slug
-func explainCipher(d description) description {
-	kexAndCipher := strings.Split(d.Slug, "_WITH_")
-	d.Name = fmt.Sprintf("%s key exchange, %s cipher", kexAndCipher[0][len("TLS_"):], kexAndCipher[1])
+// Fill in a human readable name, extracted from the slug. TLS 1.3 suites
+// (e.g. TLS_AES_128_GCM_SHA256) don't encode a key exchange, since 1.3
+// always negotiates (EC)DHE out of band.
+func explainCipher(d cipherInfo) cipherInfo {
+	name := strings.TrimPrefix(d.Slug, "TLS_")
+	if kexAndCipher := strings.SplitN(name, "_WITH_", 2); len(kexAndCipher) == 2 {
+		d.Name = fmt.Sprintf("%s key exchange, %s cipher", kexAndCipher[0], kexAndCipher[1])
+	} else {
+		d.Name = fmt.Sprintf("%s cipher", name)
+	}
 	return d
 }
 
-var cipherSuites = map[uint16]description{
-	tls.TLS_RSA_WITH_RC4_128_SHA:                {"", "TLS_RSA_WITH_RC4_128_SHA", insecure},
-	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:           {"", "TLS_RSA_WITH_3DES_EDE_CBC_SHA", insecure},
-	tls.TLS_RSA_WITH_AES_128_CBC_SHA:            {"", "TLS_RSA_WITH_AES_128_CBC_SHA", ok},
-	tls.TLS_RSA_WITH_AES_256_CBC_SHA:            {"", "TLS_RSA_WITH_AES_256_CBC_SHA", ok},
-	tls.TLS_RSA_WITH_AES_128_CBC_SHA256:         {"", "TLS_RSA_WITH_AES_128_CBC_SHA256", ok},
-	tls.TLS_RSA_WITH_AES_128_GCM_SHA256:         {"", "TLS_RSA_WITH_AES_128_GCM_SHA256", ok},
-	tls.TLS_RSA_WITH_AES_256_GCM_SHA384:         {"", "TLS_RSA_WITH_AES_256_GCM_SHA384", ok},
-	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:        {"", "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA", insecure},
-	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA:    {"", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA", ok},
-	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA:    {"", "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA", ok},
-  tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:          {"", "TLS_ECDHE_RSA_WITH_RC4_128_SHA", insecure},
-	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA:     {"", "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA", insecure},
-	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA:      {"", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", ok},
-	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA:      {"", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA", ok},
-	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256: {"", "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256", ok},
-	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256:   {"", "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256", ok},
-	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256:   {"", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", good},
-	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256: {"", "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", good},
-	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384:   {"", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384", good},
-	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384: {"", "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", good},
-	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305:    {"", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305", good},
-	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305:  {"", "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305", good},
+// assessCipher derives the security properties of a cipher suite from its
+// standard library name: whether it offers forward secrecy and AEAD, which
+// authentication and key exchange algorithms it uses, an overall quality
+// tier, and any warnings worth surfacing (e.g. "RC4 stream cipher is
+// broken"). This is how quality classification and the assessment fields
+// track the Go standard library instead of a stale hand-maintained list.
+func assessCipher(name string, suiteInsecure bool) cipherInfo {
+	info := cipherInfo{description: description{Slug: name}}
+
+	isTLS13 := strings.HasPrefix(name, "TLS_AES_") || strings.HasPrefix(name, "TLS_CHACHA20_POLY1305")
+	switch {
+	case isTLS13:
+		// TLS 1.3 suites only name the record cipher; key exchange and
+		// authentication are negotiated separately and are always
+		// ephemeral (EC)DHE, so forward secrecy is guaranteed.
+		info.ForwardSecrecy = true
+		info.AEAD = true
+	default:
+		info.ForwardSecrecy = strings.Contains(name, "ECDHE") || strings.Contains(name, "_DHE_")
+		info.AEAD = strings.Contains(name, "GCM") || strings.Contains(name, "CHACHA20")
+		switch {
+		case strings.Contains(name, "ECDSA"):
+			info.Authentication = "ECDSA"
+		case strings.Contains(name, "RSA"):
+			info.Authentication = "RSA"
+		}
+		switch {
+		case strings.Contains(name, "ECDHE"):
+			info.KeyExchange = "ECDHE"
+		case strings.Contains(name, "_DHE_"):
+			info.KeyExchange = "DHE"
+		case strings.HasPrefix(name, "TLS_RSA_"):
+			info.KeyExchange = "RSA"
+		}
+	}
+
+	switch {
+	case suiteInsecure:
+		info.Quality = insecure
+	case isTLS13:
+		info.Quality = modern
+	case info.ForwardSecrecy && info.AEAD:
+		info.Quality = good
+	case info.ForwardSecrecy || info.AEAD:
+		info.Quality = ok
+	default:
+		info.Quality = weak
+	}
+
+	if strings.Contains(name, "RC4") {
+		info.Warnings = append(info.Warnings, "RC4 stream cipher is broken")
+	}
+	if strings.Contains(name, "3DES") {
+		info.Warnings = append(info.Warnings, "3DES is vulnerable to the SWEET32 birthday attack")
+	}
+	if !info.AEAD && !strings.Contains(name, "RC4") {
+		info.Warnings = append(info.Warnings, "CBC-mode cipher lacks AEAD")
+	}
+	if !info.ForwardSecrecy {
+		info.Warnings = append(info.Warnings, "RSA key exchange does not provide forward secrecy")
+	}
+	return info
+}
+
+// cipherSuites maps cipher suite IDs to their assessment, built at init()
+// time from the standard library so classification tracks whatever Go
+// considers insecure/ok/good instead of a stale hand-maintained list.
+var cipherSuites map[uint16]cipherInfo
+
+func init() {
+	cipherSuites = make(map[uint16]cipherInfo, len(tls.CipherSuites())+len(tls.InsecureCipherSuites()))
+	for _, cs := range tls.CipherSuites() {
+		cipherSuites[cs.ID] = explainCipher(assessCipher(cs.Name, false))
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		cipherSuites[cs.ID] = explainCipher(assessCipher(cs.Name, true))
+	}
+}
+
+// lookupCipher finds the assessment for a cipher suite ID, falling back to
+// the standard library's name (rather than an UNKNOWN_%x placeholder) for
+// suites Go knows about but we haven't classified.
+func lookupCipher(suite uint16) cipherInfo {
+	if d, ok := cipherSuites[suite]; ok {
+		return d
+	}
+	return explainCipher(assessCipher(tls.CipherSuiteName(suite), false))
 }
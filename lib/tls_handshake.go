@@ -0,0 +1,161 @@
+package lib
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+)
+
+// x25519Kyber768Draft00 is the IANA codepoint for the hybrid
+// post-quantum/X25519 draft group. The standard library doesn't define a
+// constant for it (support, where present, is behind a GODEBUG flag), so
+// we keep our own copy purely for classification/display.
+const x25519Kyber768Draft00 tls.CurveID = 0x6399
+
+// Legacy, IANA-registered curves the standard library never negotiates
+// but which a classification table should still recognize and flag.
+const (
+	secp192r1 tls.CurveID = 19
+	secp224r1 tls.CurveID = 21
+)
+
+// keyExchangeGroups classifies every (EC)DHE group certigo might report,
+// keyed by the IANA "Supported Groups" codepoint.
+var keyExchangeGroups = map[uint16]description{
+	uint16(secp192r1):             {"secp192r1", "secp192r1", insecure},
+	uint16(secp224r1):             {"secp224r1", "secp224r1", insecure},
+	uint16(tls.CurveP256):         {"P-256", "p256", good},
+	uint16(tls.CurveP384):         {"P-384", "p384", good},
+	uint16(tls.CurveP521):         {"P-521", "p521", good},
+	uint16(tls.X25519):            {"X25519", "x25519", good},
+	uint16(x25519Kyber768Draft00): {"X25519Kyber768", "x25519_kyber768", modern},
+}
+
+// x509SignatureAlgorithms classifies the algorithm a certificate's issuer
+// used to sign it. crypto/tls never surfaces the signature scheme actually
+// used in the handshake's CertificateVerify step (tls.SignatureScheme is
+// only visible, on the client side, as the server's list of schemes it
+// will *accept for a client certificate* — CertificateRequestInfo — which
+// is a different thing entirely), so this is the closest real,
+// inspectable signal: how the presented leaf certificate was signed.
+var x509SignatureAlgorithms = map[x509.SignatureAlgorithm]description{
+	x509.SHA1WithRSA:      {"rsa_pkcs1_sha1", "rsa_pkcs1_sha1", insecure},
+	x509.ECDSAWithSHA1:    {"ecdsa_sha1", "ecdsa_sha1", insecure},
+	x509.SHA256WithRSA:    {"rsa_pkcs1_sha256", "rsa_pkcs1_sha256", ok},
+	x509.SHA384WithRSA:    {"rsa_pkcs1_sha384", "rsa_pkcs1_sha384", ok},
+	x509.SHA512WithRSA:    {"rsa_pkcs1_sha512", "rsa_pkcs1_sha512", ok},
+	x509.ECDSAWithSHA256:  {"ecdsa_secp256r1_sha256", "ecdsa_secp256r1_sha256", good},
+	x509.ECDSAWithSHA384:  {"ecdsa_secp384r1_sha384", "ecdsa_secp384r1_sha384", good},
+	x509.ECDSAWithSHA512:  {"ecdsa_secp521r1_sha512", "ecdsa_secp521r1_sha512", good},
+	x509.SHA256WithRSAPSS: {"rsa_pss_rsae_sha256", "rsa_pss_rsae_sha256", good},
+	x509.SHA384WithRSAPSS: {"rsa_pss_rsae_sha384", "rsa_pss_rsae_sha384", good},
+	x509.SHA512WithRSAPSS: {"rsa_pss_rsae_sha512", "rsa_pss_rsae_sha512", good},
+	x509.PureEd25519:      {"ed25519", "ed25519", modern},
+}
+
+// lookupGroup finds the description for a key-exchange group ID.
+func lookupGroup(id tls.CurveID) description {
+	if d, ok := keyExchangeGroups[uint16(id)]; ok {
+		return d
+	}
+	unknown := fmt.Sprintf("UNKNOWN_GROUP_%x", uint16(id))
+	return description{unknown, unknown, 0}
+}
+
+// lookupX509SignatureAlgorithm finds the description for a certificate
+// signature algorithm.
+func lookupX509SignatureAlgorithm(alg x509.SignatureAlgorithm) description {
+	if d, ok := x509SignatureAlgorithms[alg]; ok {
+		return d
+	}
+	unknown := fmt.Sprintf("UNKNOWN_SIG_ALG_%s", alg)
+	return description{unknown, unknown, 0}
+}
+
+// HandshakeDetails carries handshake-time information that
+// tls.ConnectionState doesn't expose directly: the negotiated
+// key-exchange group and the leaf certificate's signature algorithm.
+type HandshakeDetails struct {
+	KeyExchangeGroup description
+	SignatureScheme  description
+}
+
+// DialWithDetails is like tls.Dial, but also fills in a HandshakeDetails:
+// the signature algorithm the server's leaf certificate was signed with
+// (captured via VerifyConnection, which runs once the peer certificates
+// are available) and the negotiated key-exchange group (recovered with
+// DetectKeyExchangeGroup, since the standard library doesn't expose it on
+// tls.ConnectionState at all).
+//
+// Unlike tls.Dial, this performs up to 1+len(keyExchangeGroupOrder) TLS
+// handshakes against addr: the real connection returned to the caller,
+// plus one additional reconnect per candidate curve that
+// DetectKeyExchangeGroup pins in turn to see which one the server accepts.
+// That's unconditional — there's no way to opt out short of not calling
+// this function — so avoid it against hosts where repeated reconnects
+// might trip a rate limiter or WAF.
+func DialWithDetails(network, addr string, config *tls.Config) (*tls.Conn, *HandshakeDetails, error) {
+	details := &HandshakeDetails{}
+	if config == nil {
+		config = &tls.Config{}
+	}
+	cfg := config.Clone()
+
+	verifyConnection := cfg.VerifyConnection
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) > 0 {
+			details.SignatureScheme = lookupX509SignatureAlgorithm(cs.PeerCertificates[0].SignatureAlgorithm)
+		}
+		if verifyConnection != nil {
+			return verifyConnection(cs)
+		}
+		return nil
+	}
+
+	conn, err := tls.Dial(network, addr, cfg)
+	if err != nil {
+		return nil, details, err
+	}
+
+	if group, groupErr := DetectKeyExchangeGroup(network, addr, config); groupErr == nil {
+		details.KeyExchangeGroup = group
+	}
+
+	return conn, details, nil
+}
+
+// keyExchangeGroupOrder are the groups DetectKeyExchangeGroup tries, most
+// preferred first, matching the standard library's own default order.
+var keyExchangeGroupOrder = []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384, tls.CurveP521}
+
+// DetectKeyExchangeGroup determines which key-exchange group a server
+// will negotiate. The standard library doesn't expose the negotiated
+// group on tls.ConnectionState, so this pins CurvePreferences to one
+// candidate group at a time and dials until one succeeds — the same
+// probing technique ScanTLS uses for cipher suites. A pinned
+// CurvePreferences only constrains (EC)DHE suites, so a bare "the
+// handshake succeeded" isn't enough: a server that only offers a
+// non-ephemeral cipher suite (e.g. static RSA key exchange) will complete
+// the handshake regardless of which curve was pinned, without using any
+// curve at all. So this also checks that the negotiated cipher suite
+// actually provides forward secrecy before trusting the pinned group.
+func DetectKeyExchangeGroup(network, addr string, base *tls.Config) (description, error) {
+	if base == nil {
+		base = &tls.Config{}
+	}
+	for _, id := range keyExchangeGroupOrder {
+		cfg := base.Clone()
+		cfg.CurvePreferences = []tls.CurveID{id}
+		conn, err := tls.Dial(network, addr, cfg)
+		if err != nil {
+			continue
+		}
+		state := conn.ConnectionState()
+		conn.Close()
+		if !lookupCipher(state.CipherSuite).ForwardSecrecy {
+			return description{}, fmt.Errorf("%s did not negotiate a forward-secret cipher suite; no key-exchange group was used", addr)
+		}
+		return lookupGroup(id), nil
+	}
+	return description{}, fmt.Errorf("could not determine key-exchange group for %s", addr)
+}
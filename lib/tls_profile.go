@@ -0,0 +1,147 @@
+package lib
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+)
+
+// Profile is a named, ops-owned policy describing which TLS versions and
+// cipher suites a connection is allowed to negotiate. The built-in profiles
+// mirror the Mozilla TLS configuration generator's modern/intermediate/old
+// tiers; callers can also load their own from JSON (or YAML, since the
+// struct tags are lowercase and flat enough for a YAML decoder to reuse).
+type Profile struct {
+	Name         string   `json:"name"`
+	MinVersion   uint16   `json:"min_version"`
+	MaxVersion   uint16   `json:"max_version"`
+	CipherSuites []uint16 `json:"cipher_suites"`
+}
+
+// Violation describes one way a negotiated TLS connection fails to meet a
+// Profile's requirements.
+type Violation struct {
+	Parameter string `json:"parameter"`
+	Message   string `json:"message"`
+}
+
+// profiles holds the built-in named policies, selectable with --profile.
+var profiles = map[string]Profile{
+	"modern": {
+		Name:       "modern",
+		MinVersion: tls.VersionTLS13,
+		MaxVersion: tls.VersionTLS13,
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+		},
+	},
+	"intermediate": {
+		Name:       "intermediate",
+		MinVersion: tls.VersionTLS12,
+		MaxVersion: tls.VersionTLS13,
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+		},
+	},
+	"old": {
+		Name:       "old",
+		MinVersion: tls.VersionTLS10,
+		MaxVersion: tls.VersionTLS13,
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			tls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA,
+		},
+	},
+}
+
+// LoadProfile returns a built-in named profile (modern, intermediate, old).
+func LoadProfile(name string) (*Profile, error) {
+	p, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown TLS profile %q", name)
+	}
+	return &p, nil
+}
+
+// LoadProfilesFromJSON parses a set of named profiles from JSON, so ops
+// teams can pin their own allow-lists instead of using the built-ins.
+func LoadProfilesFromJSON(data []byte) (map[string]Profile, error) {
+	var loaded map[string]Profile
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return nil, fmt.Errorf("parsing TLS profiles: %w", err)
+	}
+	return loaded, nil
+}
+
+// VerifyTLSAgainstProfile checks a negotiated connection against a named
+// profile and returns every way it falls short. A nil/empty result means
+// the connection satisfies the profile.
+func VerifyTLSAgainstProfile(tcs *tls.ConnectionState, profile string) ([]Violation, error) {
+	p, err := LoadProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	return p.Verify(tcs), nil
+}
+
+// Verify checks a negotiated connection against this profile and returns
+// every way it falls short.
+func (p *Profile) Verify(tcs *tls.ConnectionState) []Violation {
+	var violations []Violation
+
+	if tcs.Version < p.MinVersion {
+		violations = append(violations, Violation{
+			Parameter: "version",
+			Message:   fmt.Sprintf("negotiated %s is below the %q profile's minimum of %s", lookup(tlsVersions, tcs.Version).Name, p.Name, lookup(tlsVersions, p.MinVersion).Name),
+		})
+	} else if tcs.Version > p.MaxVersion {
+		violations = append(violations, Violation{
+			Parameter: "version",
+			Message:   fmt.Sprintf("negotiated %s is above the %q profile's maximum of %s", lookup(tlsVersions, tcs.Version).Name, p.Name, lookup(tlsVersions, p.MaxVersion).Name),
+		})
+	}
+
+	if !containsSuite(p.CipherSuites, tcs.CipherSuite) {
+		violations = append(violations, Violation{
+			Parameter: "cipher_suite",
+			Message:   fmt.Sprintf("cipher suite %s is not on the %q profile's allow-list", lookupCipher(tcs.CipherSuite).Slug, p.Name),
+		})
+	}
+
+	return violations
+}
+
+func containsSuite(suites []uint16, suite uint16) bool {
+	for _, s := range suites {
+		if s == suite {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package lib
+
+import (
+	"crypto/tls"
+	"testing"
+)
+
+func TestProfileVerify(t *testing.T) {
+	modern, err := LoadProfile("modern")
+	if err != nil {
+		t.Fatalf("LoadProfile(modern): %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		state          tls.ConnectionState
+		wantViolations int
+	}{
+		{
+			name: "matches modern profile",
+			state: tls.ConnectionState{
+				Version:     tls.VersionTLS13,
+				CipherSuite: tls.TLS_AES_128_GCM_SHA256,
+			},
+			wantViolations: 0,
+		},
+		{
+			name: "version below profile minimum",
+			state: tls.ConnectionState{
+				Version:     tls.VersionTLS12,
+				CipherSuite: tls.TLS_AES_128_GCM_SHA256,
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "cipher not on the allow-list",
+			state: tls.ConnectionState{
+				Version:     tls.VersionTLS13,
+				CipherSuite: tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			},
+			wantViolations: 1,
+		},
+		{
+			name: "version and cipher both fail",
+			state: tls.ConnectionState{
+				Version:     tls.VersionTLS10,
+				CipherSuite: tls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			},
+			wantViolations: 2,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			violations := modern.Verify(&c.state)
+			if len(violations) != c.wantViolations {
+				t.Errorf("Verify() = %v, want %d violations", violations, c.wantViolations)
+			}
+		})
+	}
+}
+
+func TestLoadProfileUnknown(t *testing.T) {
+	if _, err := LoadProfile("nonexistent"); err == nil {
+		t.Error("LoadProfile(nonexistent) = nil error, want an error")
+	}
+}
+
+func TestVerifyTLSAgainstProfileOldAcceptsCBC(t *testing.T) {
+	state := &tls.ConnectionState{
+		Version:     tls.VersionTLS12,
+		CipherSuite: tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	}
+	violations, err := VerifyTLSAgainstProfile(state, "old")
+	if err != nil {
+		t.Fatalf("VerifyTLSAgainstProfile: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Errorf("VerifyTLSAgainstProfile(old) = %v, want no violations", violations)
+	}
+}
@@ -0,0 +1,228 @@
+package lib
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ScanOptions configures a ScanTLS run.
+type ScanOptions struct {
+	// Timeout bounds each individual dial. Defaults to 5s if zero.
+	Timeout time.Duration
+	// Concurrency bounds how many dials run at once. Defaults to 4 if zero.
+	Concurrency int
+}
+
+// ScanResult records whether the server accepted one particular cipher
+// suite for the TLS version it was dialed with.
+type ScanResult struct {
+	Cipher    cipherInfo
+	Supported bool
+}
+
+// ScanReport groups ScanResults per TLS version, in the style of nmap's
+// ssl-enum-ciphers script. Errors holds one entry per version/suite probe
+// that failed for a reason other than the server rejecting it (a
+// connectivity problem, a timeout, a malformed response), so a flaky scan
+// doesn't silently read as "supports nothing".
+type ScanReport struct {
+	Host    string
+	Results map[uint16][]ScanResult
+	Errors  []string
+}
+
+// scanVersions is every TLS version ScanTLS probes, oldest first.
+var scanVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13}
+
+// allCipherSuites is every cipher suite the standard library knows about,
+// good and insecure alike, used to build the per-version job list below.
+func allCipherSuites() []*tls.CipherSuite {
+	suites := make([]*tls.CipherSuite, 0, len(tls.CipherSuites())+len(tls.InsecureCipherSuites()))
+	suites = append(suites, tls.CipherSuites()...)
+	suites = append(suites, tls.InsecureCipherSuites()...)
+	return suites
+}
+
+func suiteSupportsVersion(cs *tls.CipherSuite, version uint16) bool {
+	for _, v := range cs.SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanTLS dials host once per candidate TLS version/cipher-suite
+// combination (every ID in tls.CipherSuites()+tls.InsecureCipherSuites()
+// that the standard library itself lists as valid for TLS 1.0-1.2),
+// recording which ones the server accepts. TLS 1.3 cipher suites can't be
+// pinned individually — the standard library always negotiates them
+// automatically — so TLS 1.3 is probed once per connection and whichever
+// suite the server picks is recorded as supported.
+func ScanTLS(host string, opts ScanOptions) (*ScanReport, error) {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	concurrency := opts.Concurrency
+	if concurrency == 0 {
+		concurrency = 4
+	}
+
+	type job struct {
+		version uint16
+		suite   uint16
+	}
+	var jobs []job
+	suites := allCipherSuites()
+	for _, version := range scanVersions {
+		if version == tls.VersionTLS13 {
+			jobs = append(jobs, job{version, 0})
+			continue
+		}
+		for _, cs := range suites {
+			if suiteSupportsVersion(cs, version) {
+				jobs = append(jobs, job{version, cs.ID})
+			}
+		}
+	}
+
+	results := make(map[uint16][]ScanResult, len(scanVersions))
+	var errs []string
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, j := range jobs {
+		j := j
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			supported, cipher, err := probeTLS(host, j.version, j.suite, timeout)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s/%s: %v", lookup(tlsVersions, j.version).Slug, probeLabel(j.suite), err))
+				return
+			}
+			results[j.version] = append(results[j.version], ScanResult{Cipher: cipher, Supported: supported})
+		}()
+	}
+	wg.Wait()
+
+	return &ScanReport{Host: host, Results: results, Errors: errs}, nil
+}
+
+func probeLabel(suite uint16) string {
+	if suite == 0 {
+		return "auto"
+	}
+	return lookupCipher(suite).Slug
+}
+
+// probeTLS dials host pinned to exactly one TLS version (and, outside of
+// TLS 1.3, exactly one cipher suite) and reports whether the server
+// accepted it. A handshake failure carrying an unsupported_cipher/
+// protocol_version-style alert is treated as a clean "not supported"
+// result; any other failure (connectivity, timeout, malformed response)
+// is returned as err rather than silently read as unsupported.
+func probeTLS(host string, version, suite uint16, timeout time.Duration) (supported bool, cipher cipherInfo, err error) {
+	config := &tls.Config{
+		MinVersion:         version,
+		MaxVersion:         version,
+		InsecureSkipVerify: true,
+	}
+	if suite != 0 {
+		config.CipherSuites = []uint16{suite}
+	}
+
+	conn, err := net.DialTimeout("tcp", host, timeout)
+	if err != nil {
+		return false, cipherInfo{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	tlsConn := tls.Client(conn, config)
+	if hsErr := tlsConn.Handshake(); hsErr != nil {
+		if isRejection(hsErr) {
+			if suite != 0 {
+				return false, lookupCipher(suite), nil
+			}
+			return false, cipherInfo{}, nil
+		}
+		return false, cipherInfo{}, hsErr
+	}
+
+	state := tlsConn.ConnectionState()
+	return true, lookupCipher(state.CipherSuite), nil
+}
+
+// isRejection reports whether err is the server's way of saying "I don't
+// support that", rather than a genuine connectivity problem. crypto/tls
+// doesn't give a typed, received-alert error for a plain TCP handshake
+// (tls.AlertError is only populated over QUIC); what comes back from
+// Handshake() is a *net.OpError wrapping the alert's prose description, so
+// match on the exact strings the standard library's alertText table uses
+// for "I won't negotiate this" alerts, plus the pre-alert error the server
+// hello path raises when nothing offered is acceptable.
+func isRejection(err error) bool {
+	msg := err.Error()
+	for _, alert := range []string{"handshake failure", "protocol version not supported", "insufficient security level", "no cipher suite supported by both client and server"} {
+		if strings.Contains(msg, alert) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncodeScanToObject turns a ScanReport into a JSON-marshallable value,
+// reusing TLSDescription's SupportedSuites field to report every
+// version/cipher-suite combination the server accepted.
+func EncodeScanToObject(report *ScanReport) interface{} {
+	d := &TLSDescription{SupportedSuites: map[string][]string{}}
+	for _, version := range sortedVersions(report.Results) {
+		slug := lookup(tlsVersions, version).Slug
+		for _, r := range report.Results[version] {
+			if r.Supported {
+				d.SupportedSuites[slug] = append(d.SupportedSuites[slug], r.Cipher.Slug)
+			}
+		}
+	}
+	return d
+}
+
+// EncodeScanToText renders a ScanReport as colored, human-readable text,
+// grouped per TLS version and color-coded by quality, in the style of
+// nmap's ssl-enum-ciphers script.
+func EncodeScanToText(report *ScanReport) string {
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "** TLS Scan: %s **\n", report.Host)
+	for _, version := range sortedVersions(report.Results) {
+		fmt.Fprintf(&buffer, "%s:\n", tlscolor(lookup(tlsVersions, version)))
+		for _, r := range report.Results[version] {
+			if !r.Supported {
+				continue
+			}
+			fmt.Fprintf(&buffer, "  %s\n", tlscolor(r.Cipher.description))
+		}
+	}
+	return buffer.String()
+}
+
+func sortedVersions(results map[uint16][]ScanResult) []uint16 {
+	versions := make([]uint16, 0, len(results))
+	for v := range results {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
@@ -0,0 +1,54 @@
+package lib
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestIsRejection(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "handshake failure alert",
+			err:  &net.OpError{Op: "remote error", Err: errors.New("tls: handshake failure")},
+			want: true,
+		},
+		{
+			name: "protocol version alert, e.g. dialing a TLS 1.2-only server with MaxVersion TLS10",
+			err:  &net.OpError{Op: "remote error", Err: errors.New("tls: protocol version not supported")},
+			want: true,
+		},
+		{
+			name: "insufficient security level alert",
+			err:  &net.OpError{Op: "remote error", Err: errors.New("tls: insufficient security level")},
+			want: true,
+		},
+		{
+			name: "server-side no-overlap error raised before any alert is sent",
+			err:  errors.New("tls: no cipher suite supported by both client and server"),
+			want: true,
+		},
+		{
+			name: "plain connectivity failure is not a rejection",
+			err:  errors.New("dial tcp 10.0.0.1:443: connect: connection refused"),
+			want: false,
+		},
+		{
+			name: "timeout is not a rejection",
+			err:  errors.New("dial tcp 10.0.0.1:443: i/o timeout"),
+			want: false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isRejection(c.err); got != c.want {
+				t.Errorf("isRejection(%q) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
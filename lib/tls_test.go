@@ -0,0 +1,138 @@
+package lib
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAssessCipher(t *testing.T) {
+	cases := []struct {
+		name         string
+		cipher       string
+		insecure     bool
+		wantQuality  uint8
+		wantForward  bool
+		wantAEAD     bool
+		wantAuth     string
+		wantKeyExch  string
+		wantWarnings []string
+	}{
+		{
+			name:        "tls 1.3 suite is modern and forward-secret by construction",
+			cipher:      "TLS_AES_128_GCM_SHA256",
+			wantQuality: modern,
+			wantForward: true,
+			wantAEAD:    true,
+		},
+		{
+			name:        "ecdhe ecdsa gcm is good",
+			cipher:      "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+			wantQuality: good,
+			wantForward: true,
+			wantAEAD:    true,
+			wantAuth:    "ECDSA",
+			wantKeyExch: "ECDHE",
+		},
+		{
+			name:         "ecdhe rsa cbc is ok and flags missing AEAD",
+			cipher:       "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+			wantQuality:  ok,
+			wantForward:  true,
+			wantAEAD:     false,
+			wantAuth:     "RSA",
+			wantKeyExch:  "ECDHE",
+			wantWarnings: []string{"CBC-mode cipher lacks AEAD"},
+		},
+		{
+			name:        "static rsa key exchange with gcm is ok but lacks forward secrecy",
+			cipher:      "TLS_RSA_WITH_AES_128_GCM_SHA256",
+			wantQuality: ok,
+			wantForward: false,
+			wantAEAD:    true,
+			wantAuth:    "RSA",
+			wantKeyExch: "RSA",
+			wantWarnings: []string{
+				"RSA key exchange does not provide forward secrecy",
+			},
+		},
+		{
+			name:        "explicitly insecure suite is always insecure regardless of its other properties",
+			cipher:      "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+			insecure:    true,
+			wantQuality: insecure,
+			wantForward: true,
+			wantAEAD:    true,
+			wantAuth:    "RSA",
+			wantKeyExch: "ECDHE",
+		},
+		{
+			name:        "rc4 gets its own warning instead of the generic CBC one",
+			cipher:      "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+			insecure:    true,
+			wantQuality: insecure,
+			wantForward: true,
+			wantAEAD:    false,
+			wantAuth:    "RSA",
+			wantKeyExch: "ECDHE",
+			wantWarnings: []string{
+				"RC4 stream cipher is broken",
+			},
+		},
+		{
+			name:        "3des is flagged for sweet32 on top of lacking AEAD",
+			cipher:      "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+			insecure:    true,
+			wantQuality: insecure,
+			wantForward: true,
+			wantAEAD:    false,
+			wantAuth:    "RSA",
+			wantKeyExch: "ECDHE",
+			wantWarnings: []string{
+				"3DES is vulnerable to the SWEET32 birthday attack",
+				"CBC-mode cipher lacks AEAD",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			info := assessCipher(c.cipher, c.insecure)
+			if info.Quality != c.wantQuality {
+				t.Errorf("Quality = %d, want %d", info.Quality, c.wantQuality)
+			}
+			if info.ForwardSecrecy != c.wantForward {
+				t.Errorf("ForwardSecrecy = %v, want %v", info.ForwardSecrecy, c.wantForward)
+			}
+			if info.AEAD != c.wantAEAD {
+				t.Errorf("AEAD = %v, want %v", info.AEAD, c.wantAEAD)
+			}
+			if info.Authentication != c.wantAuth {
+				t.Errorf("Authentication = %q, want %q", info.Authentication, c.wantAuth)
+			}
+			if info.KeyExchange != c.wantKeyExch {
+				t.Errorf("KeyExchange = %q, want %q", info.KeyExchange, c.wantKeyExch)
+			}
+			if !reflect.DeepEqual(info.Warnings, c.wantWarnings) {
+				t.Errorf("Warnings = %v, want %v", info.Warnings, c.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestExplainCipherName(t *testing.T) {
+	cases := []struct {
+		slug string
+		want string
+	}{
+		{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "ECDHE_RSA key exchange, AES_128_GCM_SHA256 cipher"},
+		{"TLS_AES_128_GCM_SHA256", "AES_128_GCM_SHA256 cipher"},
+	}
+	for _, c := range cases {
+		t.Run(c.slug, func(t *testing.T) {
+			got := explainCipher(cipherInfo{description: description{Slug: c.slug}})
+			if got.Name != c.want {
+				t.Errorf("explainCipher(%q).Name = %q, want %q", c.slug, got.Name, c.want)
+			}
+		})
+	}
+}